@@ -0,0 +1,113 @@
+// Package basepathfs wraps a filesystem.FS and clamps every path under a
+// root directory, so multiple Backends can safely share a parent directory
+// without one being able to escape into another's namespace.
+package basepathfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	filesystem "schneider.vip/retryspool/storage/data/filesystem"
+)
+
+// FS clamps all paths passed to it under root before delegating to inner.
+type FS struct {
+	inner filesystem.FS
+	root  string
+}
+
+// New returns an FS that resolves every path against root before delegating
+// to inner, rejecting any path that would escape root.
+func New(inner filesystem.FS, root string) *FS {
+	return &FS{inner: inner, root: filepath.Clean(root)}
+}
+
+var _ filesystem.FS = (*FS)(nil)
+
+// OSRoot reports root and true when inner is filesystem.OSFS, letting
+// Backend apply its openat2/openat symlink-escape hardening through root
+// instead of falling back to naive, symlink-following opens. Without this,
+// a symlink planted under root before any backend use could redirect a
+// write outside it, defeating the whole point of clamping paths here.
+func (f *FS) OSRoot() (string, bool) {
+	if _, ok := f.inner.(filesystem.OSFS); ok {
+		return f.root, true
+	}
+	return "", false
+}
+
+// resolve verifies that name falls under root, rejecting it otherwise. The
+// Backend always builds absolute paths from its own basePath, so name is
+// expected to already be an absolute path; resolve clamps rather than joins.
+func (f *FS) resolve(name string) (string, error) {
+	full := filepath.Clean(name)
+
+	rel, err := filepath.Rel(f.root, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("basepathfs: path %q escapes root %q", name, f.root)
+	}
+
+	return full, nil
+}
+
+func (f *FS) MkdirAll(path string, perm os.FileMode) error {
+	resolved, err := f.resolve(path)
+	if err != nil {
+		return err
+	}
+	return f.inner.MkdirAll(resolved, perm)
+}
+
+func (f *FS) Create(name string) (filesystem.File, error) {
+	resolved, err := f.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.inner.Create(resolved)
+}
+
+func (f *FS) Open(name string) (filesystem.File, error) {
+	resolved, err := f.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.inner.Open(resolved)
+}
+
+func (f *FS) Remove(name string) error {
+	resolved, err := f.resolve(name)
+	if err != nil {
+		return err
+	}
+	return f.inner.Remove(resolved)
+}
+
+func (f *FS) ReadDir(name string) ([]os.DirEntry, error) {
+	resolved, err := f.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.inner.ReadDir(resolved)
+}
+
+func (f *FS) Rename(oldpath, newpath string) error {
+	resolvedOld, err := f.resolve(oldpath)
+	if err != nil {
+		return err
+	}
+	resolvedNew, err := f.resolve(newpath)
+	if err != nil {
+		return err
+	}
+	return f.inner.Rename(resolvedOld, resolvedNew)
+}
+
+func (f *FS) Stat(name string) (os.FileInfo, error) {
+	resolved, err := f.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.inner.Stat(resolved)
+}
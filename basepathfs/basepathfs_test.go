@@ -0,0 +1,47 @@
+package basepathfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	filesystem "schneider.vip/retryspool/storage/data/filesystem"
+)
+
+func TestRejectsEscape(t *testing.T) {
+	root, err := os.MkdirTemp("", "retryspool-basepathfs-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	fs := New(filesystem.OSFS{}, root)
+
+	if _, err := fs.Stat(filepath.Dir(root)); err == nil {
+		t.Error("expected Stat above root to be rejected")
+	}
+	if _, err := fs.Stat(filepath.Join(root, "..", "escaped")); err == nil {
+		t.Error("expected Stat with '..' traversal to be rejected")
+	}
+}
+
+func TestDelegatesWithinRoot(t *testing.T) {
+	root, err := os.MkdirTemp("", "retryspool-basepathfs-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	fs := New(filesystem.OSFS{}, root)
+
+	path := filepath.Join(root, "file.data")
+	f, err := fs.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, err := fs.Stat(path); err != nil {
+		t.Errorf("Stat failed for path inside root: %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+// Package compress decorates any datastorage.Backend with transparent
+// compression, so large RFC5322 messages take less space on disk (or
+// whatever the wrapped backend stores to). Stored payloads carry a small
+// header identifying the algorithm, with a field for the original size;
+// StoreData and GetDataWriter stream their input and always write that
+// field as unknownSize, so UncompressedSize measures the size by
+// decompressing. Files written before this wrapper was introduced (no
+// header) are read back uncompressed.
+package compress
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	datastorage "schneider.vip/retryspool/storage/data"
+)
+
+// Backend wraps an inner datastorage.Backend, compressing payloads on the
+// way in and decompressing them on the way out.
+type Backend struct {
+	inner datastorage.Backend
+	opts  Options
+}
+
+var _ datastorage.Backend = (*Backend)(nil)
+
+// NewBackend wraps inner with transparent compression configured by opts.
+func NewBackend(inner datastorage.Backend, opts Options) *Backend {
+	return &Backend{inner: inner, opts: opts.withDefaults()}
+}
+
+// StoreData compresses data and stores it via the inner backend, returning
+// the compressed byte count actually written. Compression is streamed
+// through an io.Pipe rather than buffered, so memory use is bounded by the
+// codec's internal window rather than the message size.
+func (b *Backend) StoreData(ctx context.Context, messageID string, data io.Reader) (int64, error) {
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(b.encodeTo(pw, data))
+	}()
+
+	n, err := b.inner.StoreData(ctx, messageID, pr)
+	// If the inner backend stopped reading early (e.g. a write error), make
+	// sure the encoder goroutine's blocked Write unblocks instead of
+	// leaking.
+	pr.CloseWithError(err)
+
+	return n, err
+}
+
+// GetDataReader returns a reader that transparently decompresses the stored
+// payload. Data stored before compression was enabled (no header) is
+// returned unchanged.
+func (b *Backend) GetDataReader(ctx context.Context, messageID string) (io.ReadCloser, error) {
+	inner, err := b.inner.GetDataReader(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	algo, _, raw, ok, err := peekHeader(inner)
+	if err != nil {
+		inner.Close()
+		return nil, err
+	}
+
+	if !ok {
+		return &rawReadCloser{r: io.MultiReader(bytes.NewReader(raw), inner), closer: inner}, nil
+	}
+
+	switch algo {
+	case Zstd:
+		dec, err := zstd.NewReader(inner)
+		if err != nil {
+			inner.Close()
+			return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+		}
+		return &zstdReadCloser{dec: dec, inner: inner}, nil
+	default:
+		inner.Close()
+		return nil, fmt.Errorf("unsupported compression algo %d in stored header", algo)
+	}
+}
+
+// GetDataWriter returns a writer that streams each Write straight through a
+// zstd encoder into the inner backend's writer, so memory use is bounded by
+// the codec's internal window rather than the message size. The original
+// size isn't known until Close, so the header is written with a size
+// placeholder (see UncompressedSize).
+func (b *Backend) GetDataWriter(ctx context.Context, messageID string) (io.WriteCloser, error) {
+	return &compressWriter{ctx: ctx, backend: b, messageID: messageID}, nil
+}
+
+// DeleteData removes the stored (compressed) payload via the inner backend.
+func (b *Backend) DeleteData(ctx context.Context, messageID string) error {
+	return b.inner.DeleteData(ctx, messageID)
+}
+
+// Close closes the inner backend.
+func (b *Backend) Close() error {
+	return b.inner.Close()
+}
+
+// UncompressedSize returns messageID's original, pre-compression size.
+func (b *Backend) UncompressedSize(ctx context.Context, messageID string) (int64, error) {
+	inner, err := b.inner.GetDataReader(ctx, messageID)
+	if err != nil {
+		return 0, err
+	}
+	defer inner.Close()
+
+	algo, originalSize, raw, ok, err := peekHeader(inner)
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok {
+		// Legacy uncompressed file: every byte, header or not, is payload.
+		rest, err := io.Copy(io.Discard, inner)
+		if err != nil {
+			return 0, fmt.Errorf("failed to measure legacy data file: %w", err)
+		}
+		return int64(len(raw)) + rest, nil
+	}
+
+	if originalSize != unknownSize {
+		return originalSize, nil
+	}
+
+	// The header was written around a streamed payload, whose size wasn't
+	// known up front; measure it by decompressing and counting.
+	switch algo {
+	case Zstd:
+		dec, err := zstd.NewReader(inner)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create zstd decoder: %w", err)
+		}
+		defer dec.Close()
+
+		n, err := io.Copy(io.Discard, dec)
+		if err != nil {
+			return 0, fmt.Errorf("failed to measure compressed data: %w", err)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("unsupported compression algo %d in stored header", algo)
+	}
+}
+
+// encodeTo writes the header followed by data compressed per b.opts to w,
+// streaming throughout so the caller never has to hold a full copy of
+// either the plaintext or the compressed payload in memory.
+func (b *Backend) encodeTo(w io.Writer, data io.Reader) error {
+	if err := writeHeader(w, b.opts.Algo, unknownSize); err != nil {
+		return err
+	}
+
+	enc, err := newEncoder(w, b.opts)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(enc, data); err != nil {
+		enc.Close()
+		return fmt.Errorf("failed to compress data: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to finalize compressed data: %w", err)
+	}
+	return nil
+}
+
+// newEncoder wraps w with a compressor for opts.Algo. The caller is
+// responsible for having already written opts.Algo's header onto w.
+func newEncoder(w io.Writer, opts Options) (io.WriteCloser, error) {
+	switch opts.Algo {
+	case Zstd:
+		var zstdOpts []zstd.EOption
+		if opts.Level != 0 {
+			zstdOpts = append(zstdOpts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(opts.Level)))
+		}
+		enc, err := zstd.NewWriter(w, zstdOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+		return enc, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression algo %d", opts.Algo)
+	}
+}
+
+// rawReadCloser replays bytes peeked off the front of a legacy, uncompressed
+// data file ahead of the rest of it.
+type rawReadCloser struct {
+	r      io.Reader
+	closer io.Closer
+}
+
+func (r *rawReadCloser) Read(p []byte) (int, error) { return r.r.Read(p) }
+func (r *rawReadCloser) Close() error               { return r.closer.Close() }
+
+// zstdReadCloser closes both the zstd decoder and the underlying stream.
+type zstdReadCloser struct {
+	dec   *zstd.Decoder
+	inner io.ReadCloser
+}
+
+func (z *zstdReadCloser) Read(p []byte) (int, error) {
+	return z.dec.Read(p)
+}
+
+func (z *zstdReadCloser) Close() error {
+	z.dec.Close()
+	return z.inner.Close()
+}
+
+// compressWriter streams each Write through a zstd encoder straight into the
+// inner backend's writer, opened lazily on the first Write so a caller that
+// closes without writing still gets a valid (empty) compressed stream.
+type compressWriter struct {
+	ctx       context.Context
+	backend   *Backend
+	messageID string
+	inner     io.WriteCloser
+	enc       io.WriteCloser
+	closed    bool
+}
+
+// open lazily opens the inner writer, writes the header, and wraps the
+// inner writer with an encoder that Write/Close stream through.
+func (w *compressWriter) open() error {
+	if w.inner != nil {
+		return nil
+	}
+
+	inner, err := w.backend.inner.GetDataWriter(w.ctx, w.messageID)
+	if err != nil {
+		return fmt.Errorf("failed to open inner writer: %w", err)
+	}
+
+	if err := writeHeader(inner, w.backend.opts.Algo, unknownSize); err != nil {
+		inner.Close()
+		return err
+	}
+
+	enc, err := newEncoder(inner, w.backend.opts)
+	if err != nil {
+		inner.Close()
+		return err
+	}
+
+	w.inner = inner
+	w.enc = enc
+	return nil
+}
+
+// Write streams p through the encoder. On failure it releases the inner
+// writer itself (rather than leaving that to a Close the caller has no
+// reason to make, since a bytes.Buffer-backed Write never used to fail) so
+// the per-message lock and permit this writer is holding aren't leaked.
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if err := w.open(); err != nil {
+		return 0, err
+	}
+	n, err := w.enc.Write(p)
+	if err != nil {
+		w.closeOnError()
+	}
+	return n, err
+}
+
+func (w *compressWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	if err := w.enc.Close(); err != nil {
+		w.inner.Close()
+		return fmt.Errorf("failed to finalize compressed data: %w", err)
+	}
+
+	return w.inner.Close()
+}
+
+// closeOnError releases the inner writer after a failed Write, without
+// trying to flush the now-broken encoder into it.
+func (w *compressWriter) closeOnError() {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	w.inner.Close()
+}
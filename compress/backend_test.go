@@ -0,0 +1,263 @@
+package compress_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	filesystem "schneider.vip/retryspool/storage/data/filesystem"
+	"schneider.vip/retryspool/storage/data/filesystem/compress"
+)
+
+func newInnerBackend(t *testing.T) (*filesystem.Backend, string) {
+	t.Helper()
+
+	tempDir, err := os.MkdirTemp("", "retryspool-compress-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	inner, err := filesystem.NewBackend(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { inner.Close() })
+
+	return inner, tempDir
+}
+
+func TestStoreDataRoundTrip(t *testing.T) {
+	inner, _ := newInnerBackend(t)
+	backend := compress.NewBackend(inner, compress.Options{Algo: compress.Zstd, Level: 3})
+
+	ctx := context.Background()
+	messageID := "msg-roundtrip"
+	payload := []byte(strings.Repeat("retryspool payload ", 200))
+
+	compressedSize, err := backend.StoreData(ctx, messageID, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("StoreData failed: %v", err)
+	}
+	if compressedSize >= int64(len(payload)) {
+		t.Errorf("expected compressed size (%d) to be smaller than plaintext (%d)", compressedSize, len(payload))
+	}
+
+	reader, err := backend.GetDataReader(ctx, messageID)
+	if err != nil {
+		t.Fatalf("GetDataReader failed: %v", err)
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("round trip mismatch: got %d bytes, want %d bytes", len(got), len(payload))
+	}
+
+	originalSize, err := backend.UncompressedSize(ctx, messageID)
+	if err != nil {
+		t.Fatalf("UncompressedSize failed: %v", err)
+	}
+	if originalSize != int64(len(payload)) {
+		t.Errorf("UncompressedSize() = %d, want %d", originalSize, len(payload))
+	}
+}
+
+func TestGetDataWriterRoundTrip(t *testing.T) {
+	inner, _ := newInnerBackend(t)
+	backend := compress.NewBackend(inner, compress.Options{Algo: compress.Zstd})
+
+	ctx := context.Background()
+	messageID := "msg-writer"
+	payload := []byte("written through GetDataWriter")
+
+	writer, err := backend.GetDataWriter(ctx, messageID)
+	if err != nil {
+		t.Fatalf("GetDataWriter failed: %v", err)
+	}
+	if _, err := writer.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reader, err := backend.GetDataReader(ctx, messageID)
+	if err != nil {
+		t.Fatalf("GetDataReader failed: %v", err)
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("got %q, want %q", got, payload)
+	}
+}
+
+// failingStoreBackend errors out of StoreData without draining data, the
+// way a real backend would on a write error partway through a file.
+type failingStoreBackend struct {
+	*filesystem.Backend
+}
+
+func (f *failingStoreBackend) StoreData(ctx context.Context, messageID string, data io.Reader) (int64, error) {
+	return 0, errors.New("simulated write failure")
+}
+
+func TestStoreDataPropagatesInnerErrorWithoutDeadlock(t *testing.T) {
+	inner, _ := newInnerBackend(t)
+	backend := compress.NewBackend(&failingStoreBackend{inner}, compress.Options{Algo: compress.Zstd})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		payload := bytes.Repeat([]byte("large payload "), 1<<16)
+		if _, err := backend.StoreData(context.Background(), "msg-fail", bytes.NewReader(payload)); err == nil {
+			t.Error("expected StoreData to surface the inner backend's error")
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("StoreData did not return; the encoder goroutine likely deadlocked on the pipe")
+	}
+}
+
+func TestStoreDataStreamsLargePayload(t *testing.T) {
+	inner, _ := newInnerBackend(t)
+	backend := compress.NewBackend(inner, compress.Options{Algo: compress.Zstd})
+
+	ctx := context.Background()
+	messageID := "msg-large"
+	payload := bytes.Repeat([]byte("retryspool streaming payload "), 1<<16)
+
+	if _, err := backend.StoreData(ctx, messageID, bytes.NewReader(payload)); err != nil {
+		t.Fatalf("StoreData failed: %v", err)
+	}
+
+	reader, err := backend.GetDataReader(ctx, messageID)
+	if err != nil {
+		t.Fatalf("GetDataReader failed: %v", err)
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("round trip mismatch: got %d bytes, want %d bytes", len(got), len(payload))
+	}
+
+	originalSize, err := backend.UncompressedSize(ctx, messageID)
+	if err != nil {
+		t.Fatalf("UncompressedSize failed: %v", err)
+	}
+	if originalSize != int64(len(payload)) {
+		t.Errorf("UncompressedSize() = %d, want %d", originalSize, len(payload))
+	}
+}
+
+// trackingFailWriter accepts its first Write (the compress header) and
+// fails every later one, the way a real file write might fail partway
+// through (disk full, I/O error), and records whether Close was called
+// afterward.
+type trackingFailWriter struct {
+	writes      int
+	closeCalled bool
+}
+
+func (w *trackingFailWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes == 1 {
+		return len(p), nil
+	}
+	return 0, errors.New("simulated disk write failure")
+}
+
+func (w *trackingFailWriter) Close() error {
+	w.closeCalled = true
+	return nil
+}
+
+// writerOnlyBackend wraps a real backend but substitutes GetDataWriter, so
+// a test can observe what compressWriter does with the writer it's handed.
+type writerOnlyBackend struct {
+	*filesystem.Backend
+	writer io.WriteCloser
+}
+
+func (b *writerOnlyBackend) GetDataWriter(ctx context.Context, messageID string) (io.WriteCloser, error) {
+	return b.writer, nil
+}
+
+func TestGetDataWriterReleasesInnerWriterOnWriteFailure(t *testing.T) {
+	inner, _ := newInnerBackend(t)
+	failing := &trackingFailWriter{}
+	backend := compress.NewBackend(&writerOnlyBackend{inner, failing}, compress.Options{Algo: compress.Zstd})
+
+	writer, err := backend.GetDataWriter(context.Background(), "msg-write-fail")
+	if err != nil {
+		t.Fatalf("GetDataWriter failed: %v", err)
+	}
+
+	// Write enough to force zstd to flush into the (failing) inner writer
+	// rather than just buffering it internally.
+	payload := bytes.Repeat([]byte("x"), 1<<20)
+	if _, err := writer.Write(payload); err == nil {
+		t.Fatal("expected Write to surface the inner writer's failure")
+	}
+
+	if !failing.closeCalled {
+		t.Error("expected the inner writer to be closed after a failed Write, so a caller that (reasonably) doesn't call Close after a Write error still releases the writer's resources")
+	}
+}
+
+func TestGetDataReaderFallsBackForLegacyFiles(t *testing.T) {
+	inner, _ := newInnerBackend(t)
+	backend := compress.NewBackend(inner, compress.Options{Algo: compress.Zstd})
+
+	ctx := context.Background()
+	messageID := "msg-legacy"
+	payload := []byte("stored before compression existed")
+
+	// Simulate a pre-existing uncompressed file by writing directly through
+	// the inner backend, bypassing the compress wrapper.
+	if _, err := inner.StoreData(ctx, messageID, bytes.NewReader(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := backend.GetDataReader(ctx, messageID)
+	if err != nil {
+		t.Fatalf("GetDataReader failed: %v", err)
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("got %q, want %q", got, payload)
+	}
+
+	originalSize, err := backend.UncompressedSize(ctx, messageID)
+	if err != nil {
+		t.Fatalf("UncompressedSize failed: %v", err)
+	}
+	if originalSize != int64(len(payload)) {
+		t.Errorf("UncompressedSize() = %d, want %d", originalSize, len(payload))
+	}
+}
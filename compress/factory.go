@@ -0,0 +1,33 @@
+package compress
+
+import (
+	datastorage "schneider.vip/retryspool/storage/data"
+)
+
+// Factory wraps an inner datastorage.Factory, producing Backends decorated
+// with transparent compression.
+type Factory struct {
+	inner datastorage.Factory
+	opts  Options
+}
+
+var _ datastorage.Factory = (*Factory)(nil)
+
+// NewFactory wraps inner with transparent compression configured by opts.
+func NewFactory(inner datastorage.Factory, opts Options) *Factory {
+	return &Factory{inner: inner, opts: opts.withDefaults()}
+}
+
+// Create creates the inner backend and wraps it with compression.
+func (f *Factory) Create() (datastorage.Backend, error) {
+	innerBackend, err := f.inner.Create()
+	if err != nil {
+		return nil, err
+	}
+	return NewBackend(innerBackend, f.opts), nil
+}
+
+// Name returns the factory name.
+func (f *Factory) Name() string {
+	return f.inner.Name() + "+compress"
+}
@@ -0,0 +1,65 @@
+package compress
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Algo identifies the compression algorithm a stored payload was written
+// with, so GetDataReader knows how to undo it.
+type Algo byte
+
+const (
+	// None stores the payload as-is. Used internally when detecting legacy,
+	// pre-compression data files; never written by StoreData/GetDataWriter.
+	None Algo = iota
+	// Zstd compresses with github.com/klauspost/compress/zstd.
+	Zstd
+)
+
+// unknownSize marks a header written around a streamed payload, whose
+// original size wasn't known until the stream finished. UncompressedSize
+// falls back to decompressing and counting for these.
+const unknownSize int64 = -1
+
+// magic identifies a header written by this package, so GetDataReader can
+// tell a compressed payload apart from a legacy uncompressed one.
+var magic = [4]byte{'R', 'S', 'Z', '1'}
+
+// headerSize is magic (4 bytes) + algo (1 byte) + original size (8 bytes).
+const headerSize = 4 + 1 + 8
+
+// writeHeader writes the fixed-size header identifying algo and the
+// uncompressed payload size to w.
+func writeHeader(w io.Writer, algo Algo, originalSize int64) error {
+	var header [headerSize]byte
+	copy(header[0:4], magic[:])
+	header[4] = byte(algo)
+	binary.BigEndian.PutUint64(header[5:headerSize], uint64(originalSize))
+
+	_, err := w.Write(header[:])
+	return err
+}
+
+// peekHeader reads headerSize bytes from r and reports whether they form a
+// valid header. If they don't (a short read, or a legacy uncompressed file),
+// raw holds the bytes actually read so the caller can still see them.
+func peekHeader(r io.Reader) (algo Algo, originalSize int64, raw []byte, ok bool, err error) {
+	buf := make([]byte, headerSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return None, 0, buf[:n], false, nil
+		}
+		return None, 0, buf[:n], false, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	if string(buf[0:4]) != string(magic[:]) {
+		return None, 0, buf, false, nil
+	}
+
+	algo = Algo(buf[4])
+	originalSize = int64(binary.BigEndian.Uint64(buf[5:headerSize]))
+	return algo, originalSize, nil, true, nil
+}
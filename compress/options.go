@@ -0,0 +1,21 @@
+package compress
+
+// Options configures how Backend compresses stored payloads.
+type Options struct {
+	// Algo selects the compression algorithm. Defaults to Zstd (the zero
+	// value, None, would store payloads uncompressed with a header, which
+	// is pointless) if left unset.
+	Algo Algo
+	// Level is the zstd compression level, using the same scale as the
+	// zstd CLI (roughly 1-22); it is translated to the nearest
+	// zstd.EncoderLevel. 0 leaves the level unset, so the zstd package's own
+	// default (SpeedDefault) applies.
+	Level int
+}
+
+func (o Options) withDefaults() Options {
+	if o.Algo == None {
+		o.Algo = Zstd
+	}
+	return o
+}
@@ -0,0 +1,76 @@
+package filesystem
+
+import "sync"
+
+// DefaultMaxParallelOps bounds simultaneous open file handles when
+// WithMaxParallelOps isn't used, the same default Vault's physical.PermitPool
+// uses for its backends.
+const DefaultMaxParallelOps = 128
+
+// permitPool is a counting semaphore bounding the number of concurrent file
+// operations a Backend will have in flight, modeled on Vault's
+// physical.PermitPool.
+type permitPool struct {
+	sem chan struct{}
+}
+
+// newPermitPool creates a permitPool allowing up to size concurrent
+// operations. size <= 0 falls back to DefaultMaxParallelOps.
+func newPermitPool(size int) *permitPool {
+	if size <= 0 {
+		size = DefaultMaxParallelOps
+	}
+	return &permitPool{sem: make(chan struct{}, size)}
+}
+
+// acquire blocks until a permit is available.
+func (p *permitPool) acquire() {
+	p.sem <- struct{}{}
+}
+
+// release returns a permit to the pool.
+func (p *permitPool) release() {
+	<-p.sem
+}
+
+// drain blocks until every outstanding permit has been returned, by
+// acquiring the pool's entire capacity. Used by Close to wait out in-flight
+// operations before tearing down the backend.
+func (p *permitPool) drain() {
+	for i := 0; i < cap(p.sem); i++ {
+		p.sem <- struct{}{}
+	}
+}
+
+// messageLock is a refcounted mutex for a single messageID, so operations
+// against different messages never block one another.
+type messageLock struct {
+	mu  sync.Mutex
+	ref int
+}
+
+// lockMessage locks messageID, creating its lock on first use, and returns
+// the lock so the caller can hand it back to unlockMessage.
+func (b *Backend) lockMessage(messageID string) *messageLock {
+	b.locksMu.Lock()
+	actual, _ := b.locks.LoadOrStore(messageID, &messageLock{})
+	lock := actual.(*messageLock)
+	lock.ref++
+	b.locksMu.Unlock()
+
+	lock.mu.Lock()
+	return lock
+}
+
+// unlockMessage unlocks a lock obtained from lockMessage, and deletes it
+// from the map once nothing else references it.
+func (b *Backend) unlockMessage(messageID string, lock *messageLock) {
+	lock.mu.Unlock()
+
+	b.locksMu.Lock()
+	lock.ref--
+	if lock.ref == 0 {
+		b.locks.Delete(messageID)
+	}
+	b.locksMu.Unlock()
+}
@@ -0,0 +1,166 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithMaxParallelOpsBoundsConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewBackend(dir, WithMaxParallelOps(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	var inFlight int32
+	var maxObserved int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+
+			messageID := "concurrent-" + string(rune('a'+n))
+			backend.permits.acquire()
+			cur := atomic.AddInt32(&inFlight, 1)
+			for {
+				observed := atomic.LoadInt32(&maxObserved)
+				if cur <= observed || atomic.CompareAndSwapInt32(&maxObserved, observed, cur) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			backend.permits.release()
+
+			if _, err := backend.StoreData(ctx, messageID, bytes.NewReader([]byte("x"))); err != nil {
+				t.Errorf("StoreData failed: %v", err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if maxObserved > 2 {
+		t.Errorf("observed %d concurrent permits, want at most 2", maxObserved)
+	}
+}
+
+func TestPerMessageLocksDoNotBlockAcrossMessages(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	lockA := backend.lockMessage("a")
+	unlocked := make(chan struct{})
+
+	go func() {
+		lockB := backend.lockMessage("b")
+		defer backend.unlockMessage("b", lockB)
+		close(unlocked)
+	}()
+
+	select {
+	case <-unlocked:
+	case <-time.After(time.Second):
+		t.Fatal("locking message b blocked on message a's lock")
+	}
+
+	backend.unlockMessage("a", lockA)
+}
+
+func TestCloseDrainsInFlightPermits(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewBackend(dir, WithMaxParallelOps(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend.permits.acquire()
+
+	closed := make(chan struct{})
+	go func() {
+		backend.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("Close returned before the in-flight permit was released")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	backend.permits.release()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return after the in-flight permit was released")
+	}
+}
+
+func TestWithWriteBytesPerSecThrottlesStoreData(t *testing.T) {
+	dir := t.TempDir()
+	const bytesPerSec = 1024
+	backend, err := NewBackend(dir, WithWriteBytesPerSec(bytesPerSec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	payload := bytes.Repeat([]byte("x"), bytesPerSec*2)
+
+	start := time.Now()
+	if _, err := backend.StoreData(context.Background(), "throttled-write", bytes.NewReader(payload)); err != nil {
+		t.Fatalf("StoreData failed: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if elapsed < 500*time.Millisecond {
+		t.Errorf("StoreData of %d bytes at %d bytes/sec took %v, expected throttling to take longer", len(payload), bytesPerSec, elapsed)
+	}
+}
+
+func TestWithReadBytesPerSecThrottlesGetDataReader(t *testing.T) {
+	dir := t.TempDir()
+	const bytesPerSec = 1024
+	backend, err := NewBackend(dir, WithReadBytesPerSec(bytesPerSec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	payload := bytes.Repeat([]byte("y"), bytesPerSec*2)
+	if _, err := backend.StoreData(ctx, "throttled-read", bytes.NewReader(payload)); err != nil {
+		t.Fatalf("StoreData failed: %v", err)
+	}
+
+	reader, err := backend.GetDataReader(ctx, "throttled-read")
+	if err != nil {
+		t.Fatalf("GetDataReader failed: %v", err)
+	}
+	defer reader.Close()
+
+	start := time.Now()
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if elapsed < 500*time.Millisecond {
+		t.Errorf("reading %d bytes at %d bytes/sec took %v, expected throttling to take longer", len(payload), bytesPerSec, elapsed)
+	}
+}
@@ -7,18 +7,20 @@ import (
 // Factory implements datastorage.Factory for filesystem storage
 type Factory struct {
 	basePath string
+	opts     []Option
 }
 
 // NewFactory creates a new filesystem data storage factory
-func NewFactory(basePath string) *Factory {
+func NewFactory(basePath string, opts ...Option) *Factory {
 	return &Factory{
 		basePath: basePath,
+		opts:     opts,
 	}
 }
 
 // Create creates a new filesystem data storage backend
 func (f *Factory) Create() (datastorage.Backend, error) {
-	return NewBackend(f.basePath)
+	return NewBackend(f.basePath, f.opts...)
 }
 
 // Name returns the factory name
@@ -8,24 +8,87 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+
+	"golang.org/x/time/rate"
 )
 
 // Backend implements datastorage.Backend for filesystem storage
 type Backend struct {
-	basePath string
-	mu       sync.RWMutex
+	basePath      string
+	durableWrites bool
+	fs            FS
+	openatMode    openatMode
+	openatModeErr error
+	pathOpener    pathOpener
+	// pathOpenerBase is basePath expressed relative to pathOpener's own
+	// root. It is left empty when that root is basePath itself, and is set
+	// only when the configured FS is an osRooted wrapper whose root sits
+	// above basePath.
+	pathOpenerBase string
+
+	maxParallelOps int
+	permits        *permitPool
+
+	readLimiter  *rate.Limiter
+	writeLimiter *rate.Limiter
+
+	locksMu sync.Mutex
+	locks   sync.Map // map[string]*messageLock
+
+	closedMu sync.RWMutex
 	closed   bool
 }
 
 // NewBackend creates a new filesystem data storage backend
-func NewBackend(basePath string) (*Backend, error) {
-	if err := os.MkdirAll(basePath, 0o755); err != nil {
+func NewBackend(basePath string, opts ...Option) (*Backend, error) {
+	b := &Backend{
+		basePath:      basePath,
+		durableWrites: true,
+		fs:            OSFS{},
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.openatModeErr != nil {
+		return nil, b.openatModeErr
+	}
+
+	if err := b.fs.MkdirAll(basePath, 0o755); err != nil {
 		return nil, fmt.Errorf("failed to create base directory: %w", err)
 	}
 
-	return &Backend{
-		basePath: basePath,
-	}, nil
+	// The openat2/openat hardening only makes sense against the real
+	// filesystem. A custom FS from WithFS owns its own path safety, unless
+	// it identifies itself as a wrapper around the real filesystem rooted
+	// at a known directory (e.g. basepathfs.FS over OSFS), in which case we
+	// still apply the hardened opener against that root.
+	if _, isOSFS := b.fs.(OSFS); isOSFS {
+		opener, err := newPathOpener(basePath, b.openatMode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize path opener: %w", err)
+		}
+		b.pathOpener = opener
+	} else if rooted, ok := b.fs.(osRooted); ok {
+		if root, ok := rooted.OSRoot(); ok {
+			rel, err := filepath.Rel(root, basePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve base path against FS root: %w", err)
+			}
+
+			opener, err := newPathOpener(root, b.openatMode)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize path opener: %w", err)
+			}
+			b.pathOpener = opener
+			b.pathOpenerBase = rel
+		}
+	}
+
+	b.permits = newPermitPool(b.maxParallelOps)
+
+	return b, nil
 }
 
 // StoreData stores message data and returns the actual size written
@@ -34,11 +97,8 @@ func (b *Backend) StoreData(ctx context.Context, messageID string, data io.Reade
 		return 0, err
 	}
 
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	if b.closed {
-		return 0, fmt.Errorf("backend is closed")
+	if err := b.checkOpen(); err != nil {
+		return 0, err
 	}
 
 	select {
@@ -47,24 +107,27 @@ func (b *Backend) StoreData(ctx context.Context, messageID string, data io.Reade
 	default:
 	}
 
-	dataPath := b.getDataPath(messageID)
+	b.permits.acquire()
+	defer b.permits.release()
 
-	// Create directory if needed
-	if err := os.MkdirAll(filepath.Dir(dataPath), 0o755); err != nil {
-		return 0, fmt.Errorf("failed to create data directory: %w", err)
-	}
+	lock := b.lockMessage(messageID)
+	defer b.unlockMessage(messageID, lock)
 
-	dataFile, err := os.Create(dataPath)
+	writer, err := b.newDataFileWriter(ctx, messageID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create data file: %w", err)
+		return 0, err
 	}
-	defer dataFile.Close()
 
-	size, err := io.Copy(dataFile, data)
+	size, err := io.Copy(writer, data)
 	if err != nil {
+		writer.abort()
 		return 0, fmt.Errorf("failed to write data: %w", err)
 	}
 
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
 	return size, nil
 }
 
@@ -74,11 +137,8 @@ func (b *Backend) GetDataReader(ctx context.Context, messageID string) (io.ReadC
 		return nil, err
 	}
 
-	b.mu.RLock()
-	defer b.mu.RUnlock()
-
-	if b.closed {
-		return nil, fmt.Errorf("backend is closed")
+	if err := b.checkOpen(); err != nil {
+		return nil, err
 	}
 
 	select {
@@ -87,8 +147,19 @@ func (b *Backend) GetDataReader(ctx context.Context, messageID string) (io.ReadC
 	default:
 	}
 
-	dataPath := b.getDataPath(messageID)
-	file, err := os.Open(dataPath)
+	b.permits.acquire()
+	defer b.permits.release()
+
+	lock := b.lockMessage(messageID)
+	defer b.unlockMessage(messageID, lock)
+
+	var file File
+	var err error
+	if b.pathOpener != nil {
+		file, err = b.pathOpener.open(b.pathOpenerRelPath(messageID), os.O_RDONLY, 0)
+	} else {
+		file, err = b.fs.Open(b.getDataPath(messageID))
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, fmt.Errorf("message data not found: %s", messageID)
@@ -96,7 +167,10 @@ func (b *Backend) GetDataReader(ctx context.Context, messageID string) (io.ReadC
 		return nil, fmt.Errorf("failed to open data file: %w", err)
 	}
 
-	return file, nil
+	if b.readLimiter == nil {
+		return file, nil
+	}
+	return &throttledReadCloser{r: &throttledReader{r: file, ctx: ctx, limiter: b.readLimiter}, closer: file}, nil
 }
 
 // GetDataWriter returns a writer for message data
@@ -105,11 +179,8 @@ func (b *Backend) GetDataWriter(ctx context.Context, messageID string) (io.Write
 		return nil, err
 	}
 
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	if b.closed {
-		return nil, fmt.Errorf("backend is closed")
+	if err := b.checkOpen(); err != nil {
+		return nil, err
 	}
 
 	select {
@@ -118,19 +189,19 @@ func (b *Backend) GetDataWriter(ctx context.Context, messageID string) (io.Write
 	default:
 	}
 
-	dataPath := b.getDataPath(messageID)
-
-	// Create directory if needed
-	if err := os.MkdirAll(filepath.Dir(dataPath), 0o755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
-	}
+	b.permits.acquire()
+	lock := b.lockMessage(messageID)
 
-	file, err := os.Create(dataPath)
+	writer, err := b.newDataFileWriter(ctx, messageID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create data file: %w", err)
+		b.unlockMessage(messageID, lock)
+		b.permits.release()
+		return nil, err
 	}
+	writer.lock = lock
+	writer.releasePermit = b.permits.release
 
-	return file, nil
+	return writer, nil
 }
 
 // DeleteData removes message data
@@ -139,11 +210,8 @@ func (b *Backend) DeleteData(ctx context.Context, messageID string) error {
 		return err
 	}
 
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	if b.closed {
-		return fmt.Errorf("backend is closed")
+	if err := b.checkOpen(); err != nil {
+		return err
 	}
 
 	select {
@@ -152,8 +220,20 @@ func (b *Backend) DeleteData(ctx context.Context, messageID string) error {
 	default:
 	}
 
+	b.permits.acquire()
+	defer b.permits.release()
+
+	lock := b.lockMessage(messageID)
+	defer b.unlockMessage(messageID, lock)
+
 	dataPath := b.getDataPath(messageID)
-	err := os.Remove(dataPath)
+
+	var err error
+	if b.pathOpener != nil {
+		err = b.pathOpener.unlink(b.pathOpenerRelPath(messageID))
+	} else {
+		err = b.fs.Remove(dataPath)
+	}
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete data file: %w", err)
 	}
@@ -164,17 +244,39 @@ func (b *Backend) DeleteData(ctx context.Context, messageID string) error {
 	return nil
 }
 
-// Close closes the data storage backend
+// Close closes the data storage backend. It waits for every in-flight
+// StoreData/GetDataReader/GetDataWriter/DeleteData call to release its
+// permit before tearing down the path opener, so no operation is left
+// writing to an already-closed backend.
 func (b *Backend) Close() error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
+	b.closedMu.Lock()
 	b.closed = true
+	b.closedMu.Unlock()
+
+	b.permits.drain()
+
+	if b.pathOpener != nil {
+		if err := b.pathOpener.close(); err != nil {
+			return fmt.Errorf("failed to close path opener: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // Helper methods
 
+// checkOpen reports an error once Close has been called.
+func (b *Backend) checkOpen() error {
+	b.closedMu.RLock()
+	defer b.closedMu.RUnlock()
+
+	if b.closed {
+		return fmt.Errorf("backend is closed")
+	}
+	return nil
+}
+
 func (b *Backend) validateMessageID(messageID string) error {
 	if messageID == "" {
 		return fmt.Errorf("messageID cannot be empty")
@@ -192,11 +294,33 @@ func (b *Backend) validateMessageID(messageID string) error {
 }
 
 func (b *Backend) getDataPath(messageID string) string {
+	return filepath.Join(b.basePath, b.getShardRelPath(messageID))
+}
+
+// getShardRelPath returns messageID's data path relative to basePath, e.g.
+// "ab/abcdef.data". Used by pathOpener, which resolves paths beneath its own
+// root fd rather than through an absolute path.
+func (b *Backend) getShardRelPath(messageID string) string {
 	// Use first 2 characters for directory sharding to avoid too many files in one directory
 	if len(messageID) >= 2 {
-		return filepath.Join(b.basePath, messageID[:2], messageID+".data")
+		return filepath.Join(messageID[:2], messageID+".data")
 	}
-	return filepath.Join(b.basePath, "misc", messageID+".data")
+	return filepath.Join("misc", messageID+".data")
+}
+
+// pathOpenerRelPath returns messageID's data path relative to pathOpener's
+// root, which differs from getShardRelPath's basePath-relative result when
+// pathOpener was rooted above basePath (an osRooted FS wrapper).
+func (b *Backend) pathOpenerRelPath(messageID string) string {
+	return filepath.Join(b.pathOpenerBase, b.getShardRelPath(messageID))
+}
+
+// osRooted is implemented by FS wrappers that ultimately resolve paths
+// against the real OS filesystem rooted at a known directory (basepathfs.FS
+// wrapping OSFS does), so NewBackend can still apply openat2/openat path
+// hardening through that root instead of falling back to naive opens.
+type osRooted interface {
+	OSRoot() (root string, ok bool)
 }
 
 func (b *Backend) cleanupEmptyDirs(dir string) {
@@ -206,14 +330,178 @@ func (b *Backend) cleanupEmptyDirs(dir string) {
 	}
 
 	// Check if directory is empty
-	entries, err := os.ReadDir(dir)
+	entries, err := b.fs.ReadDir(dir)
 	if err != nil || len(entries) > 0 {
 		return
 	}
 
 	// Remove empty directory
-	if err := os.Remove(dir); err == nil {
+	if err := b.fs.Remove(dir); err == nil {
 		// Recursively clean up parent directory
 		b.cleanupEmptyDirs(filepath.Dir(dir))
 	}
 }
+
+// dataFileWriter writes to a temp file beside the final data path and only
+// makes the write visible on Close, via fsync+rename. This ensures readers
+// never observe a partially written ".data" file, even across a crash.
+type dataFileWriter struct {
+	backend   *Backend
+	ctx       context.Context
+	file      File
+	tempPath  string
+	finalPath string
+	shardDir  string
+	viaOpener bool
+	closed    bool
+
+	// messageID, lock, and releasePermit are only set when the writer
+	// outlives the call that created it (GetDataWriter); StoreData holds
+	// and releases its own lock and permit around the whole operation.
+	messageID     string
+	lock          *messageLock
+	releasePermit func()
+}
+
+// newDataFileWriter creates the sibling temp file that backs a durable write
+// to messageID's data path.
+func (b *Backend) newDataFileWriter(ctx context.Context, messageID string) (*dataFileWriter, error) {
+	dataPath := b.getDataPath(messageID)
+	shardDir := filepath.Dir(dataPath)
+
+	if err := b.fs.MkdirAll(shardDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	if b.pathOpener != nil {
+		relPath := b.pathOpenerRelPath(messageID)
+		tempRelPath := filepath.Join(filepath.Dir(relPath), filepath.Base(relPath)+".tmp-"+randSuffix())
+
+		tempFile, err := b.pathOpener.open(tempRelPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp data file: %w", err)
+		}
+
+		return &dataFileWriter{
+			backend:   b,
+			ctx:       ctx,
+			file:      tempFile,
+			tempPath:  tempRelPath,
+			finalPath: relPath,
+			shardDir:  shardDir,
+			viaOpener: true,
+			messageID: messageID,
+		}, nil
+	}
+
+	tempFile, tempPath, err := b.createTempFile(shardDir, filepath.Base(dataPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp data file: %w", err)
+	}
+
+	return &dataFileWriter{
+		backend:   b,
+		ctx:       ctx,
+		file:      tempFile,
+		tempPath:  tempPath,
+		finalPath: dataPath,
+		shardDir:  shardDir,
+		messageID: messageID,
+	}, nil
+}
+
+// Write implements io.Writer by writing straight to the temp file, throttled
+// to the backend's write limiter when one is configured.
+func (w *dataFileWriter) Write(p []byte) (int, error) {
+	if w.backend.writeLimiter == nil {
+		return w.file.Write(p)
+	}
+	tw := &throttledWriter{w: w.file, ctx: w.ctx, limiter: w.backend.writeLimiter}
+	return tw.Write(p)
+}
+
+// Close syncs the temp file, renames it into place, and (unless durable
+// writes are disabled) fsyncs the shard directory so the rename itself
+// survives a crash. On any failure the temp file is removed.
+func (w *dataFileWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.release()
+
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		w.removeTemp()
+		return fmt.Errorf("failed to sync data file: %w", err)
+	}
+
+	if err := w.file.Close(); err != nil {
+		w.removeTemp()
+		return fmt.Errorf("failed to close data file: %w", err)
+	}
+
+	if err := w.rename(); err != nil {
+		w.removeTemp()
+		return fmt.Errorf("failed to rename data file into place: %w", err)
+	}
+
+	if w.backend.durableWrites {
+		if err := w.backend.fsyncDir(w.shardDir); err != nil {
+			return fmt.Errorf("failed to fsync data directory: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// abort discards the temp file without publishing it, used when the caller
+// fails before reaching Close (e.g. io.Copy returned an error).
+func (w *dataFileWriter) abort() {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	defer w.release()
+
+	w.file.Close()
+	w.removeTemp()
+}
+
+// release returns this writer's message lock and permit, if GetDataWriter
+// handed them off for the caller's use past the call that created them.
+func (w *dataFileWriter) release() {
+	if w.lock != nil {
+		w.backend.unlockMessage(w.messageID, w.lock)
+	}
+	if w.releasePermit != nil {
+		w.releasePermit()
+	}
+}
+
+func (w *dataFileWriter) rename() error {
+	if w.viaOpener {
+		return w.backend.pathOpener.rename(w.tempPath, w.finalPath)
+	}
+	return w.backend.fs.Rename(w.tempPath, w.finalPath)
+}
+
+func (w *dataFileWriter) removeTemp() {
+	if w.viaOpener {
+		w.backend.pathOpener.unlink(w.tempPath)
+		return
+	}
+	w.backend.fs.Remove(w.tempPath)
+}
+
+// fsyncDir opens dir and fsyncs it, which is what makes a preceding rename
+// into that directory durable on POSIX filesystems.
+func (b *Backend) fsyncDir(dir string) error {
+	d, err := b.fs.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory for fsync: %w", err)
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
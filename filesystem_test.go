@@ -0,0 +1,114 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreDataNoLeftoverTempFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "retryspool-data-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	backend, err := NewBackend(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	messageID := "msg-atomic"
+
+	if _, err := backend.StoreData(ctx, messageID, bytes.NewReader([]byte("hello world"))); err != nil {
+		t.Fatalf("StoreData failed: %v", err)
+	}
+
+	shardDir := filepath.Dir(backend.getDataPath(messageID))
+	entries, err := os.ReadDir(shardDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != messageID+".data" {
+		t.Fatalf("expected only the final data file in %s, got %v", shardDir, entries)
+	}
+
+	reader, err := backend.GetDataReader(ctx, messageID)
+	if err != nil {
+		t.Fatalf("GetDataReader failed: %v", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello world" {
+		t.Errorf("got %q, want %q", data, "hello world")
+	}
+}
+
+func TestStoreDataAbortsOnCopyError(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "retryspool-data-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	backend, err := NewBackend(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	messageID := "msg-fail"
+
+	_, err = backend.StoreData(ctx, messageID, &failingReader{})
+	if err == nil {
+		t.Fatal("expected StoreData to fail")
+	}
+
+	shardDir := filepath.Dir(backend.getDataPath(messageID))
+	entries, err := os.ReadDir(shardDir)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no leftover temp files in %s, got %v", shardDir, entries)
+	}
+}
+
+func TestWithDurableWritesFalse(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "retryspool-data-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	backend, err := NewBackend(tempDir, WithDurableWrites(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	if backend.durableWrites {
+		t.Fatal("expected durableWrites to be false")
+	}
+
+	ctx := context.Background()
+	if _, err := backend.StoreData(ctx, "msg-fast", bytes.NewReader([]byte("data"))); err != nil {
+		t.Fatalf("StoreData failed: %v", err)
+	}
+}
+
+type failingReader struct{}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
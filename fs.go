@@ -0,0 +1,68 @@
+package filesystem
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// File is the subset of *os.File that Backend relies on. It is satisfied by
+// *os.File itself, so OSFS requires no wrapping.
+type File interface {
+	Read(p []byte) (int, error)
+	Write(p []byte) (int, error)
+	Close() error
+	Name() string
+	Sync() error
+}
+
+// FS abstracts the filesystem calls Backend makes, so callers can swap in an
+// in-memory FS for tests, a base-path-clamped FS to share a parent directory
+// between backends, or any other layering. OSFS is the default.
+type FS interface {
+	MkdirAll(path string, perm os.FileMode) error
+	Create(name string) (File, error)
+	Open(name string) (File, error)
+	Remove(name string) error
+	ReadDir(name string) ([]os.DirEntry, error)
+	Rename(oldpath, newpath string) error
+	Stat(name string) (os.FileInfo, error)
+}
+
+// OSFS implements FS on top of the real operating system filesystem.
+type OSFS struct{}
+
+func (OSFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFS) Create(name string) (File, error)             { return os.Create(name) }
+func (OSFS) Open(name string) (File, error)               { return os.Open(name) }
+func (OSFS) Remove(name string) error                     { return os.Remove(name) }
+func (OSFS) ReadDir(name string) ([]os.DirEntry, error)   { return os.ReadDir(name) }
+func (OSFS) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+func (OSFS) Stat(name string) (os.FileInfo, error)        { return os.Stat(name) }
+
+// createTempFile creates a uniquely named file under dir (base+".tmp-<rand>")
+// via b.fs, retrying on name collisions. It plays the role of os.CreateTemp
+// for FS implementations that don't expose an O_EXCL primitive.
+func (b *Backend) createTempFile(dir, base string) (File, string, error) {
+	const attempts = 100
+	for i := 0; i < attempts; i++ {
+		name := filepath.Join(dir, base+".tmp-"+randSuffix())
+		if _, err := b.fs.Stat(name); err == nil {
+			continue
+		}
+		f, err := b.fs.Create(name)
+		if err != nil {
+			return nil, "", err
+		}
+		return f, name, nil
+	}
+	return nil, "", fmt.Errorf("failed to allocate a unique temp file name in %s", dir)
+}
+
+func randSuffix() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,115 @@
+package filesystem_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	filesystem "schneider.vip/retryspool/storage/data/filesystem"
+	"schneider.vip/retryspool/storage/data/filesystem/basepathfs"
+	"schneider.vip/retryspool/storage/data/filesystem/memfs"
+)
+
+func TestBackendWithMemFS(t *testing.T) {
+	backend, err := filesystem.NewBackend("/spool", filesystem.WithFS(memfs.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	messageID := "msg-mem"
+
+	if _, err := backend.StoreData(ctx, messageID, bytes.NewReader([]byte("hello"))); err != nil {
+		t.Fatalf("StoreData failed: %v", err)
+	}
+
+	reader, err := backend.GetDataReader(ctx, messageID)
+	if err != nil {
+		t.Fatalf("GetDataReader failed: %v", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("got %q, want %q", data, "hello")
+	}
+
+	if err := backend.DeleteData(ctx, messageID); err != nil {
+		t.Fatalf("DeleteData failed: %v", err)
+	}
+	if exists, _, err := backend.Exists(ctx, messageID); err != nil || exists {
+		t.Errorf("expected message to be gone, exists=%v err=%v", exists, err)
+	}
+}
+
+func TestBackendWithBasePathFS(t *testing.T) {
+	root, err := os.MkdirTemp("", "retryspool-basepathfs-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	backend, err := filesystem.NewBackend(root, filesystem.WithFS(basepathfs.New(filesystem.OSFS{}, root)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	if _, err := backend.StoreData(ctx, "msg-clamped", bytes.NewReader([]byte("payload"))); err != nil {
+		t.Fatalf("StoreData failed: %v", err)
+	}
+
+	reader, err := backend.GetDataReader(ctx, "msg-clamped")
+	if err != nil {
+		t.Fatalf("GetDataReader failed: %v", err)
+	}
+	reader.Close()
+}
+
+// TestBackendWithBasePathFSRejectsSymlinkEscape plants a shard directory
+// symlink pointing outside root before the backend ever touches it, the
+// same setup chunk0-4's hardening defends against for a bare OSFS backend.
+// basepathfs must not be able to smuggle a write past that hardening just
+// because it sits in front of OSFS.
+func TestBackendWithBasePathFSRejectsSymlinkEscape(t *testing.T) {
+	root, err := os.MkdirTemp("", "retryspool-basepathfs-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	outside, err := os.MkdirTemp("", "retryspool-basepathfs-outside-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+
+	// messageID "msgescape" shards into "ms/msgescape.data"; plant "ms" as a
+	// symlink out of root before the backend exists.
+	if err := os.Symlink(outside, filepath.Join(root, "ms")); err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := filesystem.NewBackend(root, filesystem.WithFS(basepathfs.New(filesystem.OSFS{}, root)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	if _, err := backend.StoreData(ctx, "msgescape", bytes.NewReader([]byte("payload"))); err == nil {
+		t.Fatal("expected StoreData to reject the symlinked shard directory, got nil error")
+	}
+
+	if _, err := os.Stat(filepath.Join(outside, "msgescape.data")); err == nil {
+		t.Fatal("StoreData wrote through the symlink into the directory outside root")
+	}
+}
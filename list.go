@@ -0,0 +1,164 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ListEntry describes one message found while walking the spool.
+type ListEntry struct {
+	MessageID string
+	Size      int64
+	Err       error
+}
+
+// List walks the sharded directory tree and streams one ListEntry per
+// stored message on the returned channel. The walk stops promptly and the
+// channel is closed if ctx is canceled before it completes.
+func (b *Backend) List(ctx context.Context) (<-chan ListEntry, error) {
+	if err := b.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	entries := make(chan ListEntry)
+	go func() {
+		defer close(entries)
+		b.walk(ctx, entries)
+	}()
+
+	return entries, nil
+}
+
+// walk enumerates every shard directory under basePath and emits a
+// ListEntry for each "<id>.data" file it finds.
+func (b *Backend) walk(ctx context.Context, entries chan<- ListEntry) {
+	shardDirs, err := b.fs.ReadDir(b.basePath)
+	if err != nil {
+		b.sendEntry(ctx, entries, ListEntry{Err: fmt.Errorf("failed to read base directory: %w", err)})
+		return
+	}
+
+	for _, shardDir := range shardDirs {
+		if !shardDir.IsDir() {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		shardPath := filepath.Join(b.basePath, shardDir.Name())
+		files, err := b.fs.ReadDir(shardPath)
+		if err != nil {
+			if !b.sendEntry(ctx, entries, ListEntry{Err: fmt.Errorf("failed to read shard directory %s: %w", shardPath, err)}) {
+				return
+			}
+			continue
+		}
+
+		for _, file := range files {
+			messageID, ok := messageIDFromDataFile(file.Name())
+			if !ok {
+				continue
+			}
+
+			info, err := file.Info()
+			if err != nil {
+				if !b.sendEntry(ctx, entries, ListEntry{MessageID: messageID, Err: fmt.Errorf("failed to stat %s: %w", file.Name(), err)}) {
+					return
+				}
+				continue
+			}
+
+			if !b.sendEntry(ctx, entries, ListEntry{MessageID: messageID, Size: info.Size()}) {
+				return
+			}
+		}
+	}
+}
+
+// sendEntry delivers entry on the channel, returning false if ctx was
+// canceled first so the caller can stop walking.
+func (b *Backend) sendEntry(ctx context.Context, entries chan<- ListEntry, entry ListEntry) bool {
+	select {
+	case entries <- entry:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// messageIDFromDataFile decodes a shard directory entry name back to the
+// messageID it stores, e.g. "abc123.data" -> "abc123".
+func messageIDFromDataFile(name string) (string, bool) {
+	const suffix = ".data"
+	if !strings.HasSuffix(name, suffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(name, suffix), true
+}
+
+// Exists reports whether messageID has data stored, along with its size.
+func (b *Backend) Exists(ctx context.Context, messageID string) (bool, int64, error) {
+	if err := b.validateMessageID(messageID); err != nil {
+		return false, 0, err
+	}
+
+	if err := b.checkOpen(); err != nil {
+		return false, 0, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return false, 0, ctx.Err()
+	default:
+	}
+
+	info, err := b.fs.Stat(b.getDataPath(messageID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, 0, nil
+		}
+		return false, 0, fmt.Errorf("failed to stat data file: %w", err)
+	}
+
+	return true, info.Size(), nil
+}
+
+// Size returns the stored size of messageID's data.
+func (b *Backend) Size(ctx context.Context, messageID string) (int64, error) {
+	if err := b.validateMessageID(messageID); err != nil {
+		return 0, err
+	}
+
+	if err := b.checkOpen(); err != nil {
+		return 0, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	info, err := b.fs.Stat(b.getDataPath(messageID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, fmt.Errorf("message data not found: %s", messageID)
+		}
+		return 0, fmt.Errorf("failed to stat data file: %w", err)
+	}
+
+	return info.Size(), nil
+}
@@ -0,0 +1,138 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+)
+
+func TestListEnumeratesStoredMessages(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "retryspool-data-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	backend, err := NewBackend(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	want := map[string]int64{}
+	for _, id := range []string{"msg-one", "msg-two", "msg-three"} {
+		payload := []byte("payload-" + id)
+		if _, err := backend.StoreData(ctx, id, bytes.NewReader(payload)); err != nil {
+			t.Fatalf("StoreData(%s) failed: %v", id, err)
+		}
+		want[id] = int64(len(payload))
+	}
+
+	ch, err := backend.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	got := map[string]int64{}
+	for entry := range ch {
+		if entry.Err != nil {
+			t.Fatalf("unexpected entry error: %v", entry.Err)
+		}
+		got[entry.MessageID] = entry.Size
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d: %v", len(got), len(want), got)
+	}
+	for id, size := range want {
+		if got[id] != size {
+			t.Errorf("entry %s: got size %d, want %d", id, got[id], size)
+		}
+	}
+}
+
+func TestListStopsOnContextCancel(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "retryspool-data-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	backend, err := NewBackend(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 50; i++ {
+		id := "msg-" + string(rune('a'+i%26)) + string(rune('0'+i/26))
+		_, _ = backend.StoreData(ctx, id, bytes.NewReader([]byte("data")))
+	}
+
+	ch, err := backend.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	cancel()
+
+	// The channel must still be drainable and close promptly even though
+	// the context was canceled mid-walk.
+	for range ch {
+	}
+}
+
+func TestExistsAndSize(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "retryspool-data-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	backend, err := NewBackend(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	exists, _, err := backend.Exists(ctx, "missing")
+	if err != nil {
+		t.Fatalf("Exists failed: %v", err)
+	}
+	if exists {
+		t.Error("expected missing message to not exist")
+	}
+
+	if _, err := backend.Size(ctx, "missing"); err == nil {
+		t.Error("expected Size of missing message to fail")
+	}
+
+	payload := []byte("some data")
+	if _, err := backend.StoreData(ctx, "present", bytes.NewReader(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	exists, size, err := backend.Exists(ctx, "present")
+	if err != nil {
+		t.Fatalf("Exists failed: %v", err)
+	}
+	if !exists {
+		t.Fatal("expected present message to exist")
+	}
+	if size != int64(len(payload)) {
+		t.Errorf("Exists size = %d, want %d", size, len(payload))
+	}
+
+	size, err = backend.Size(ctx, "present")
+	if err != nil {
+		t.Fatalf("Size failed: %v", err)
+	}
+	if size != int64(len(payload)) {
+		t.Errorf("Size = %d, want %d", size, len(payload))
+	}
+}
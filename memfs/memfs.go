@@ -0,0 +1,253 @@
+// Package memfs provides an in-memory implementation of filesystem.FS for
+// use in unit tests, so callers can exercise Backend without touching disk.
+package memfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	filesystem "schneider.vip/retryspool/storage/data/filesystem"
+)
+
+// FS is an in-memory filesystem.FS. The zero value is not usable; use New.
+type FS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+// New returns an empty in-memory filesystem rooted at "/".
+func New() *FS {
+	return &FS{
+		files: make(map[string][]byte),
+		dirs:  map[string]bool{clean("/"): true},
+	}
+}
+
+var _ filesystem.FS = (*FS)(nil)
+
+func clean(name string) string {
+	return filepath.Clean(name)
+}
+
+// MkdirAll registers path and all of its ancestors as directories.
+func (f *FS) MkdirAll(path string, perm os.FileMode) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for dir := clean(path); ; dir = filepath.Dir(dir) {
+		f.dirs[dir] = true
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+	}
+	return nil
+}
+
+// Create creates (or truncates) name, failing if its parent directory
+// doesn't exist, mirroring os.Create.
+func (f *FS) Create(name string) (filesystem.File, error) {
+	name = clean(name)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.dirs[filepath.Dir(name)] {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	f.files[name] = nil
+	return &file{fs: f, name: name, writable: true}, nil
+}
+
+// Open opens name for reading. Opening a directory returns a handle whose
+// Sync/Close are no-ops, which is enough for Backend's fsync-the-parent-dir
+// use case.
+func (f *FS) Open(name string) (filesystem.File, error) {
+	name = clean(name)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if data, ok := f.files[name]; ok {
+		return &file{fs: f, name: name, data: append([]byte(nil), data...)}, nil
+	}
+	if f.dirs[name] {
+		return &file{fs: f, name: name, isDir: true}, nil
+	}
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+// Remove deletes a file, or an empty directory.
+func (f *FS) Remove(name string) error {
+	name = clean(name)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.files[name]; ok {
+		delete(f.files, name)
+		return nil
+	}
+	if f.dirs[name] {
+		for child := range f.files {
+			if filepath.Dir(child) == name {
+				return &os.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+			}
+		}
+		for dir := range f.dirs {
+			if dir != name && filepath.Dir(dir) == name {
+				return &os.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+			}
+		}
+		delete(f.dirs, name)
+		return nil
+	}
+	return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+}
+
+// ReadDir lists the direct children of name, sorted by filename.
+func (f *FS) ReadDir(name string) ([]os.DirEntry, error) {
+	name = clean(name)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.dirs[name] {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	var entries []os.DirEntry
+	for path, data := range f.files {
+		if filepath.Dir(path) == name {
+			entries = append(entries, dirEntry{name: filepath.Base(path), size: int64(len(data))})
+		}
+	}
+	for dir := range f.dirs {
+		if dir != name && filepath.Dir(dir) == name {
+			entries = append(entries, dirEntry{name: filepath.Base(dir), isDir: true})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Rename moves a file from oldpath to newpath.
+func (f *FS) Rename(oldpath, newpath string) error {
+	oldpath, newpath = clean(oldpath), clean(newpath)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.files[oldpath]
+	if !ok {
+		return &os.PathError{Op: "rename", Path: oldpath, Err: os.ErrNotExist}
+	}
+	if !f.dirs[filepath.Dir(newpath)] {
+		return &os.PathError{Op: "rename", Path: newpath, Err: os.ErrNotExist}
+	}
+
+	f.files[newpath] = data
+	delete(f.files, oldpath)
+	return nil
+}
+
+// Stat returns file or directory metadata for name.
+func (f *FS) Stat(name string) (os.FileInfo, error) {
+	name = clean(name)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if data, ok := f.files[name]; ok {
+		return fileInfo{name: filepath.Base(name), size: int64(len(data))}, nil
+	}
+	if f.dirs[name] {
+		return fileInfo{name: filepath.Base(name), isDir: true}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+// file implements filesystem.File over a byte slice held by FS.
+type file struct {
+	fs       *FS
+	name     string
+	data     []byte
+	pos      int
+	writable bool
+	isDir    bool
+	closed   bool
+}
+
+func (h *file) Read(p []byte) (int, error) {
+	if h.isDir {
+		return 0, &os.PathError{Op: "read", Path: h.name, Err: fs.ErrInvalid}
+	}
+	if h.pos >= len(h.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, h.data[h.pos:])
+	h.pos += n
+	return n, nil
+}
+
+func (h *file) Write(p []byte) (int, error) {
+	if h.isDir || !h.writable {
+		return 0, &os.PathError{Op: "write", Path: h.name, Err: fs.ErrInvalid}
+	}
+
+	h.fs.mu.Lock()
+	defer h.fs.mu.Unlock()
+
+	h.fs.files[h.name] = append(h.fs.files[h.name], p...)
+	return len(p), nil
+}
+
+func (h *file) Close() error {
+	h.closed = true
+	return nil
+}
+
+func (h *file) Name() string { return h.name }
+
+func (h *file) Sync() error { return nil }
+
+// dirEntry implements os.DirEntry.
+type dirEntry struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+func (e dirEntry) Name() string { return e.name }
+func (e dirEntry) IsDir() bool  { return e.isDir }
+func (e dirEntry) Type() fs.FileMode {
+	if e.isDir {
+		return fs.ModeDir
+	}
+	return 0
+}
+func (e dirEntry) Info() (fs.FileInfo, error) {
+	return fileInfo{name: e.name, size: e.size, isDir: e.isDir}, nil
+}
+
+// fileInfo implements os.FileInfo.
+type fileInfo struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return 0 }
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+func (i fileInfo) IsDir() bool        { return i.isDir }
+func (i fileInfo) Sys() interface{}   { return nil }
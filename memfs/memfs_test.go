@@ -0,0 +1,66 @@
+package memfs
+
+import (
+	"io"
+	"testing"
+)
+
+func TestCreateWriteReadRename(t *testing.T) {
+	fs := New()
+
+	if err := fs.MkdirAll("/spool/ab", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.Create("/spool/ab/id.data.tmp-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Rename("/spool/ab/id.data.tmp-1", "/spool/ab/id.data"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := fs.Open("/spool/ab/id.data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("got %q, want %q", data, "hello")
+	}
+
+	info, err := fs.Stat("/spool/ab/id.data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 5 {
+		t.Errorf("Size() = %d, want 5", info.Size())
+	}
+
+	entries, err := fs.ReadDir("/spool/ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "id.data" {
+		t.Errorf("ReadDir() = %v", entries)
+	}
+
+	if err := fs.Remove("/spool/ab/id.data"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("/spool/ab/id.data"); err == nil {
+		t.Error("expected Stat to fail after Remove")
+	}
+}
@@ -0,0 +1,63 @@
+package filesystem
+
+import "fmt"
+
+// openatMode controls how paths beneath basePath are resolved on the real
+// OS filesystem (the default OSFS, or an osRooted FS wrapper).
+type openatMode int
+
+const (
+	openatAuto openatMode = iota
+	openatOpenat2
+	openatOpenat
+)
+
+func parseOpenatMode(mode string) (openatMode, error) {
+	switch mode {
+	case "", "auto":
+		return openatAuto, nil
+	case "openat2":
+		return openatOpenat2, nil
+	case "openat":
+		return openatOpenat, nil
+	default:
+		return 0, fmt.Errorf("unknown openat mode %q (want \"auto\", \"openat2\", or \"openat\")", mode)
+	}
+}
+
+// WithOpenatMode selects how the default OSFS resolves paths beneath
+// basePath: "auto" (the default) uses unix.Openat2 with RESOLVE_BENEATH when
+// the running kernel supports it, falling back to plain openat otherwise.
+// "openat2" and "openat" force one or the other; "openat2" fails at
+// NewBackend time if the kernel doesn't support it. Has no effect on
+// non-Linux platforms, or when a custom FS is supplied via WithFS, unless
+// that FS is an osRooted wrapper over the real filesystem (such as
+// basepathfs.FS over OSFS), in which case the mode applies beneath its root.
+func WithOpenatMode(mode string) Option {
+	return func(b *Backend) {
+		parsed, err := parseOpenatMode(mode)
+		if err != nil {
+			b.openatModeErr = err
+			return
+		}
+		b.openatMode = parsed
+	}
+}
+
+// pathOpener resolves paths beneath a root directory that was opened once,
+// so a symlink (or a racing rename) planted under basePath can't redirect a
+// later open outside of it. It is wired up for the default OSFS and for an
+// osRooted FS wrapper (rooted at the wrapper's root); any other custom FS
+// from WithFS is responsible for its own path safety.
+type pathOpener interface {
+	// open opens basePath/relPath, where relPath is "<shard>/<file>",
+	// prefixed by Backend.pathOpenerBase when the opener is rooted above
+	// the Backend's basePath.
+	open(relPath string, flags int, perm uint32) (File, error)
+	// rename moves basePath/oldRelPath to basePath/newRelPath.
+	rename(oldRelPath, newRelPath string) error
+	// unlink removes basePath/relPath.
+	unlink(relPath string) error
+	// close releases the opener's root file descriptor, if any.
+	close() error
+}
@@ -0,0 +1,140 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// openat2Supported probes the kernel once and caches the result, the same
+// way Wings does it, since the probe itself is cheap but not free.
+var openat2Supported = sync.OnceValue(func() bool {
+	fd, err := unix.Openat2(unix.AT_FDCWD, "/", &unix.OpenHow{Flags: unix.O_RDONLY})
+	if err != nil {
+		return false
+	}
+	unix.Close(fd)
+	return true
+})
+
+// linuxPathOpener resolves paths beneath a root directory fd opened once in
+// NewBackend, using openat2(RESOLVE_BENEATH|RESOLVE_NO_SYMLINKS|RESOLVE_NO_MAGICLINKS)
+// when available and falling back to plain openat otherwise.
+type linuxPathOpener struct {
+	basePath   string
+	rootFD     int
+	useOpenat2 bool
+}
+
+func newPathOpener(basePath string, mode openatMode) (pathOpener, error) {
+	rootFD, err := unix.Open(basePath, unix.O_DIRECTORY|unix.O_RDONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open base directory %q: %w", basePath, err)
+	}
+
+	useOpenat2 := false
+	switch mode {
+	case openatOpenat2:
+		if !openat2Supported() {
+			unix.Close(rootFD)
+			return nil, fmt.Errorf("openat2 requested via WithOpenatMode but not supported by this kernel")
+		}
+		useOpenat2 = true
+	case openatOpenat:
+		useOpenat2 = false
+	default: // openatAuto
+		useOpenat2 = openat2Supported()
+	}
+
+	return &linuxPathOpener{basePath: basePath, rootFD: rootFD, useOpenat2: useOpenat2}, nil
+}
+
+// openRelative opens name beneath dirFD, via openat2 when enabled and plain
+// openat otherwise.
+func (o *linuxPathOpener) openRelative(dirFD int, name string, flags int, perm uint32) (int, error) {
+	if o.useOpenat2 {
+		how := unix.OpenHow{
+			Flags:   uint64(flags),
+			Mode:    uint64(perm),
+			Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_SYMLINKS | unix.RESOLVE_NO_MAGICLINKS,
+		}
+		return unix.Openat2(dirFD, name, &how)
+	}
+	return unix.Openat(dirFD, name, flags, perm)
+}
+
+// openShardDir opens the shard directory component of relPath, relative to
+// the backend's root fd.
+func (o *linuxPathOpener) openShardDir(shardDir string) (int, error) {
+	fd, err := o.openRelative(o.rootFD, shardDir, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return -1, fmt.Errorf("failed to open shard directory %q: %w", shardDir, err)
+	}
+	return fd, nil
+}
+
+func (o *linuxPathOpener) open(relPath string, flags int, perm uint32) (File, error) {
+	shardDir, name := filepath.Split(relPath)
+	shardDir = filepath.Clean(shardDir)
+
+	shardFD, err := o.openShardDir(shardDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(shardFD)
+
+	fileFD, err := o.openRelative(shardFD, name, flags, perm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %q beneath shard directory %q: %w", name, shardDir, err)
+	}
+
+	return os.NewFile(uintptr(fileFD), filepath.Join(o.basePath, relPath)), nil
+}
+
+func (o *linuxPathOpener) rename(oldRelPath, newRelPath string) error {
+	oldShardDir, oldName := filepath.Split(oldRelPath)
+	oldShardDir = filepath.Clean(oldShardDir)
+
+	oldShardFD, err := o.openShardDir(oldShardDir)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(oldShardFD)
+
+	newShardDir, newName := filepath.Split(newRelPath)
+	newShardDir = filepath.Clean(newShardDir)
+
+	newShardFD := oldShardFD
+	if newShardDir != oldShardDir {
+		fd, err := o.openShardDir(newShardDir)
+		if err != nil {
+			return err
+		}
+		defer unix.Close(fd)
+		newShardFD = fd
+	}
+
+	return unix.Renameat(oldShardFD, oldName, newShardFD, newName)
+}
+
+func (o *linuxPathOpener) unlink(relPath string) error {
+	shardDir, name := filepath.Split(relPath)
+	shardDir = filepath.Clean(shardDir)
+
+	shardFD, err := o.openShardDir(shardDir)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(shardFD)
+
+	return unix.Unlinkat(shardFD, name, 0)
+}
+
+func (o *linuxPathOpener) close() error {
+	return unix.Close(o.rootFD)
+}
@@ -0,0 +1,59 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinuxPathOpenerRenameRejectsSymlinkedShardDir(t *testing.T) {
+	if !openat2Supported() {
+		t.Skip("openat2 not supported in this environment")
+	}
+
+	tempDir, err := os.MkdirTemp("", "retryspool-openat-rename-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	outside, err := os.MkdirTemp("", "retryspool-openat-rename-outside-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+
+	if err := os.MkdirAll(filepath.Join(tempDir, "ab"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	opener, err := newPathOpener(tempDir, openatOpenat2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer opener.close()
+
+	// Swap the legitimate shard directory for a symlink after the opener
+	// was created, simulating an attacker racing between the temp file's
+	// creation and the rename that publishes it. The symlink target holds
+	// old.data, so a rename that followed the symlink would succeed.
+	if err := os.RemoveAll(filepath.Join(tempDir, "ab")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(tempDir, "ab")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "old.data"), []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := opener.rename("ab/old.data", "ab/new.data"); err == nil {
+		t.Fatal("expected rename through a symlinked shard directory to be rejected")
+	}
+
+	if _, err := os.Stat(filepath.Join(outside, "new.data")); err == nil {
+		t.Errorf("expected nothing renamed into the directory outside basePath, found new.data")
+	}
+}
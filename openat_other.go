@@ -0,0 +1,35 @@
+//go:build !linux
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// genericPathOpener is the non-Linux fallback: there is no openat2, so it
+// just resolves basePath/relPath directly through the os package. WithOpenatMode
+// has no effect on these platforms.
+type genericPathOpener struct {
+	basePath string
+}
+
+func newPathOpener(basePath string, _ openatMode) (pathOpener, error) {
+	return &genericPathOpener{basePath: basePath}, nil
+}
+
+func (o *genericPathOpener) open(relPath string, flags int, perm uint32) (File, error) {
+	return os.OpenFile(filepath.Join(o.basePath, relPath), flags, os.FileMode(perm))
+}
+
+func (o *genericPathOpener) rename(oldRelPath, newRelPath string) error {
+	return os.Rename(filepath.Join(o.basePath, oldRelPath), filepath.Join(o.basePath, newRelPath))
+}
+
+func (o *genericPathOpener) unlink(relPath string) error {
+	return os.Remove(filepath.Join(o.basePath, relPath))
+}
+
+func (o *genericPathOpener) close() error {
+	return nil
+}
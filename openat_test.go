@@ -0,0 +1,105 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenatModes(t *testing.T) {
+	for _, mode := range []string{"auto", "openat", "openat2"} {
+		t.Run(mode, func(t *testing.T) {
+			tempDir, err := os.MkdirTemp("", "retryspool-openat-test-*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			backend, err := NewBackend(tempDir, WithOpenatMode(mode))
+			if err != nil {
+				t.Fatalf("NewBackend(%q) failed: %v", mode, err)
+			}
+			defer backend.Close()
+
+			ctx := context.Background()
+			messageID := "msg-openat"
+			payload := []byte("payload for " + mode)
+
+			if _, err := backend.StoreData(ctx, messageID, bytes.NewReader(payload)); err != nil {
+				t.Fatalf("StoreData failed: %v", err)
+			}
+
+			reader, err := backend.GetDataReader(ctx, messageID)
+			if err != nil {
+				t.Fatalf("GetDataReader failed: %v", err)
+			}
+			data, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != string(payload) {
+				t.Errorf("got %q, want %q", data, payload)
+			}
+
+			if err := backend.DeleteData(ctx, messageID); err != nil {
+				t.Fatalf("DeleteData failed: %v", err)
+			}
+			if exists, _, err := backend.Exists(ctx, messageID); err != nil || exists {
+				t.Errorf("expected message gone, exists=%v err=%v", exists, err)
+			}
+		})
+	}
+}
+
+func TestOpenatModeInvalid(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "retryspool-openat-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if _, err := NewBackend(tempDir, WithOpenatMode("bogus")); err == nil {
+		t.Fatal("expected NewBackend with an invalid openat mode to fail")
+	}
+}
+
+func TestOpenatRejectsSymlinkEscape(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "retryspool-openat-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	outside, err := os.MkdirTemp("", "retryspool-openat-outside-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+
+	backend, err := NewBackend(tempDir, WithOpenatMode("openat2"))
+	if err != nil {
+		t.Skipf("openat2 not supported in this environment: %v", err)
+	}
+	defer backend.Close()
+
+	// Plant a shard directory that is actually a symlink pointing outside
+	// basePath, as if an attacker raced a legitimate shard dir creation.
+	shardLink := filepath.Join(tempDir, "ab")
+	if err := os.Symlink(outside, shardLink); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	_, err = backend.StoreData(ctx, "abcdef", bytes.NewReader([]byte("data")))
+	if err == nil {
+		t.Fatal("expected StoreData to refuse writing through a symlinked shard directory")
+	}
+
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("expected nothing written outside basePath, found %v", entries)
+	}
+}
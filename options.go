@@ -0,0 +1,60 @@
+package filesystem
+
+import "golang.org/x/time/rate"
+
+// Option configures a Backend (or the Backends created by a Factory).
+type Option func(*Backend)
+
+// WithDurableWrites controls whether the parent shard directory is fsync'd
+// after each rename-into-place. This makes the rename itself durable across
+// a crash, at the cost of an extra fsync per write. It is enabled by
+// default; pass WithDurableWrites(false) to trade durability for throughput.
+func WithDurableWrites(durable bool) Option {
+	return func(b *Backend) {
+		b.durableWrites = durable
+	}
+}
+
+// WithFS overrides the filesystem implementation used by the Backend. This
+// is how callers plug in an in-memory FS for tests or a base-path-clamped
+// FS to share a parent directory between backends. Defaults to OSFS{}.
+func WithFS(fs FS) Option {
+	return func(b *Backend) {
+		b.fs = fs
+	}
+}
+
+// WithMaxParallelOps bounds the number of StoreData/GetDataReader/
+// GetDataWriter/DeleteData calls the Backend will have in flight at once,
+// the way Vault's physical.PermitPool bounds concurrent backend requests.
+// A writer from GetDataWriter holds its permit until it is closed. A reader
+// from GetDataReader does not: its permit is returned when GetDataReader
+// returns, so readers left open are not counted against the limit.
+// Defaults to DefaultMaxParallelOps.
+func WithMaxParallelOps(n int) Option {
+	return func(b *Backend) {
+		b.maxParallelOps = n
+	}
+}
+
+// WithReadBytesPerSec caps aggregate read throughput across GetDataReader
+// streams, the way Docker's blkio ThrottleDevice caps a container's disk
+// bandwidth. A value <= 0 (the default) leaves reads unthrottled.
+func WithReadBytesPerSec(bytesPerSec int) Option {
+	return func(b *Backend) {
+		if bytesPerSec > 0 {
+			b.readLimiter = rate.NewLimiter(rate.Limit(bytesPerSec), bytesPerSec)
+		}
+	}
+}
+
+// WithWriteBytesPerSec caps aggregate write throughput across StoreData and
+// GetDataWriter streams. A value <= 0 (the default) leaves writes
+// unthrottled.
+func WithWriteBytesPerSec(bytesPerSec int) Option {
+	return func(b *Backend) {
+		if bytesPerSec > 0 {
+			b.writeLimiter = rate.NewLimiter(rate.Limit(bytesPerSec), bytesPerSec)
+		}
+	}
+}
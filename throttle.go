@@ -0,0 +1,75 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// throttledReader wraps an io.Reader, blocking after each Read until limiter
+// has tokens for the bytes just read, capping the achievable throughput.
+type throttledReader struct {
+	r       io.Reader
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if werr := waitN(t.ctx, t.limiter, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// throttledWriter wraps an io.Writer the same way throttledReader wraps an
+// io.Reader, capping write throughput.
+type throttledWriter struct {
+	w       io.Writer
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	if n > 0 {
+		if werr := waitN(t.ctx, t.limiter, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// throttledReadCloser pairs a throttledReader with the Closer of the stream
+// it wraps, so GetDataReader can still return an io.ReadCloser.
+type throttledReadCloser struct {
+	r      io.Reader
+	closer io.Closer
+}
+
+func (t *throttledReadCloser) Read(p []byte) (int, error) { return t.r.Read(p) }
+func (t *throttledReadCloser) Close() error               { return t.closer.Close() }
+
+// waitN reserves n tokens from limiter, chunked to stay within limiter's
+// burst size so a single large read/write doesn't exceed WaitN's per-call
+// token limit.
+func waitN(ctx context.Context, limiter *rate.Limiter, n int) error {
+	burst := limiter.Burst()
+	if burst <= 0 {
+		burst = 1
+	}
+	for n > 0 {
+		chunk := n
+		if chunk > burst {
+			chunk = burst
+		}
+		if err := limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}